@@ -0,0 +1,211 @@
+// Command cl runs a shell command across every host in a named cluster.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-bi/cl/pkg/cl"
+)
+
+var codes = map[os.Signal]int{
+	syscall.SIGINT:  130,
+	syscall.SIGKILL: 137,
+}
+
+func main() {
+	argv0 := os.Args[0]
+
+	var useAgent, tofu, checksum bool
+	var knownHostsPath string
+	var concurrency int
+
+	flag.BoolVar(&useAgent, "A", false, "authenticate via ssh-agent (SSH_AUTH_SOCK)")
+	flag.BoolVar(&useAgent, "agent", false, "authenticate via ssh-agent (SSH_AUTH_SOCK)")
+	flag.BoolVar(&tofu, "tofu", false, "trust and record unknown host keys (trust on first use)")
+	flag.BoolVar(&tofu, "accept-new", false, "trust and record unknown host keys (trust on first use)")
+	flag.StringVar(&knownHostsPath, "known-hosts", "", "path to known_hosts (default ~/.ssh/known_hosts)")
+	flag.IntVar(&concurrency, "j", 0, "maximum number of hosts to dial at once (default unbounded)")
+	flag.BoolVar(&checksum, "checksum", false, "with put/get, skip files whose remote SHA-256 already matches")
+	flag.Parse()
+
+	args := flag.Args()
+
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: cl [-A] [-tofu] [-j N] [-known-hosts path] [cluster] [commands...]\n")
+		fmt.Fprintf(os.Stderr, "       cl [flags] [cluster] put [local] [remote]\n")
+		fmt.Fprintf(os.Stderr, "       cl [flags] [cluster] get [remote] [local-dir]\n")
+		os.Exit(1)
+	}
+
+	f, err := os.Open("hosts")
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", argv0, err)
+		os.Exit(1)
+	}
+
+	defer f.Close()
+
+	opts := []cl.Option{
+		cl.WithHosts(f),
+		cl.WithIdentityFiles(identityFiles()...),
+		cl.WithKnownHosts(knownHostsPath),
+		cl.WithLogger(os.Stderr),
+	}
+
+	if useAgent {
+		opts = append(opts, cl.WithAgent())
+	}
+
+	if tofu {
+		opts = append(opts, cl.WithTOFU())
+	}
+
+	if concurrency > 0 {
+		opts = append(opts, cl.WithConcurrency(concurrency))
+	}
+
+	if checksum {
+		opts = append(opts, cl.WithChecksum())
+	}
+
+	runner := cl.NewRunner(opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGKILL)
+
+	code := 0
+
+	go func() {
+		sig := <-sigs
+		cancel()
+		code = codes[sig]
+	}()
+
+	var results <-chan cl.Result
+	var printOutput bool
+
+	switch {
+	case len(args) == 4 && args[1] == "put":
+		results, err = runner.Put(ctx, args[0], args[2], args[3])
+	case len(args) == 4 && args[1] == "get":
+		results, err = runner.Get(ctx, args[0], args[2], args[3])
+	default:
+		cmd := strings.Join(args[1:], " ")
+		results, err = runner.Run(ctx, args[0], cmd)
+		printOutput = true
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", argv0, err)
+		os.Exit(1)
+	}
+
+	var failures []cl.Result
+
+	for res := range results {
+		if res.Err != nil {
+			code = 1
+			failures = append(failures, res)
+
+			var hke *cl.HostKeyMismatchError
+			var uhe *cl.UnknownHostError
+
+			switch {
+			case errors.As(res.Err, &hke):
+				fmt.Fprintf(os.Stderr, "%s: HOST KEY CHANGED: %s\n", argv0, hke)
+			case errors.As(res.Err, &uhe):
+				fmt.Fprintf(os.Stderr, "%s: %s: unknown host, rerun with -tofu to trust it\n", argv0, uhe)
+			default:
+				fmt.Fprintf(os.Stderr, "%s: %s: %s\n", argv0, res.Host.Addr, res.Err)
+			}
+
+			continue
+		}
+
+		if res.ExitCode != 0 {
+			code = 1
+		}
+
+		if printOutput {
+			fmt.Fprintf(os.Stderr, "Host: %s\n", res.Host.Addr)
+			os.Stdout.Write(indent(res.Stdout))
+			os.Stderr.Write(indent(res.Stderr))
+		} else {
+			fmt.Fprintf(os.Stderr, "Host: %s: ok\n", res.Host.Addr)
+		}
+	}
+
+	printFailureSummary(failures)
+
+	os.Exit(code)
+}
+
+func printFailureSummary(failures []cl.Result) {
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "\nfailures:")
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tATTEMPTS\tELAPSED\tERROR")
+
+	for _, res := range failures {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", res.Host.Addr, res.Attempts, res.Duration.Round(time.Millisecond), res.Err)
+	}
+
+	w.Flush()
+}
+
+func identityFiles() []string {
+	entries, err := ioutil.ReadDir("certs")
+
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			files = append(files, filepath.Join("certs", e.Name()))
+		}
+	}
+
+	return files
+}
+
+func indent(b []byte) []byte {
+	var out []byte
+	line := make([]byte, 0)
+
+	for _, c := range b {
+		line = append(line, c)
+
+		if c == '\n' {
+			out = append(out, append([]byte("  "), line...)...)
+			line = nil
+		}
+	}
+
+	if len(line) > 0 {
+		out = append(out, append([]byte("  "), line...)...)
+	}
+
+	return out
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdentityFilesSkipsDirectories(t *testing.T) {
+	dir := t.TempDir()
+	certsDir := filepath.Join(dir, "certs")
+
+	if err := os.Mkdir(certsDir, 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(certsDir, "id_rsa"), []byte("not a real key"), 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	if err := os.Mkdir(filepath.Join(certsDir, "subdir"), 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %s", err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %s", err)
+	}
+	defer os.Chdir(cwd)
+
+	files := identityFiles()
+
+	if len(files) != 1 || files[0] != filepath.Join("certs", "id_rsa") {
+		t.Fatalf("identityFiles() = %v, want [certs/id_rsa]", files)
+	}
+}
@@ -0,0 +1,213 @@
+package cl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ttyMu serializes password and passphrase prompts across the per-host
+// goroutines a Runner spawns, so concurrent prompts don't garble each
+// other on the shared terminal.
+var ttyMu sync.Mutex
+
+// passphrases caches passphrases already entered for a given key file, so
+// a fleet-wide run prompts once per unique key instead of once per host.
+var passphrases = struct {
+	mu sync.Mutex
+	m  map[string]string
+}{m: make(map[string]string)}
+
+// passwords caches passwords already entered for a given host, so a host
+// with several identity files that all fail prompts once instead of once
+// per identity file.
+var passwords = struct {
+	mu sync.Mutex
+	m  map[string]string
+}{m: make(map[string]string)}
+
+// agentAuthMethod dials the ssh-agent listening on SSH_AUTH_SOCK once per
+// Runner and returns an AuthMethod that offers every identity it holds.
+// The connection is kept open and reused for the Runner's lifetime, rather
+// than redialed on every dial() call, which would leak one fd per identity
+// file, per retry attempt, per host.
+func (rn *Runner) agentAuthMethod() (ssh.AuthMethod, error) {
+	rn.agentOnce.Do(func() {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+
+		if sock == "" {
+			rn.agentErr = fmt.Errorf("SSH_AUTH_SOCK not set")
+			return
+		}
+
+		conn, err := net.Dial("unix", sock)
+
+		if err != nil {
+			rn.agentErr = err
+			return
+		}
+
+		rn.agentClient = agent.NewClient(conn)
+	})
+
+	if rn.agentErr != nil {
+		return nil, rn.agentErr
+	}
+
+	return ssh.PublicKeysCallback(rn.agentClient.Signers), nil
+}
+
+// parseSigner parses an identity file, transparently handling passphrase-
+// protected PEM and OpenSSH-format keys (RSA, ECDSA, ed25519). The
+// passphrase is sourced from CL_KEY_PASSPHRASE_<basename> (with every
+// character of the basename other than an ASCII letter or digit turned into
+// '_'), falling back to an interactive prompt cached per key file.
+func parseSigner(cert string, key []byte) (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey(key)
+
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return signer, err
+	}
+
+	passphrase, err := keyPassphrase(cert)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+}
+
+func keyPassphrase(cert string) (string, error) {
+	envName := "CL_KEY_PASSPHRASE_" + sanitizeEnvName(filepath.Base(cert))
+
+	if p := os.Getenv(envName); p != "" {
+		return p, nil
+	}
+
+	passphrases.mu.Lock()
+	defer passphrases.mu.Unlock()
+
+	if p, ok := passphrases.m[cert]; ok {
+		return p, nil
+	}
+
+	passphrase, err := promptTTY(fmt.Sprintf("Passphrase for %s: ", cert))
+
+	if err != nil {
+		return "", err
+	}
+
+	passphrases.m[cert] = passphrase
+
+	return passphrase, nil
+}
+
+// resolvePassword finds a password for h, in order: the CL_SSH_PASSWORD
+// env var, the host's "password=" entry in the hosts file, or an
+// interactive prompt read from /dev/tty (never stdin, which may be piped).
+// Prompted passwords are cached per host, so a host with several identity
+// files that all fail is only prompted for once.
+func resolvePassword(h Host) (string, error) {
+	if p := os.Getenv("CL_SSH_PASSWORD"); p != "" {
+		return p, nil
+	}
+
+	if h.Password != "" {
+		return h.Password, nil
+	}
+
+	key := h.User + "@" + h.Addr
+
+	passwords.mu.Lock()
+	defer passwords.mu.Unlock()
+
+	if p, ok := passwords.m[key]; ok {
+		return p, nil
+	}
+
+	password, err := promptTTY(fmt.Sprintf("Password for %s: ", key))
+
+	if err != nil {
+		return "", err
+	}
+
+	passwords.m[key] = password
+
+	return password, nil
+}
+
+func promptTTY(prompt string) (string, error) {
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+
+	line, err := bufio.NewReader(tty).ReadString('\n')
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\n"), nil
+}
+
+func sanitizeEnvName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// isAuthFailure reports whether err came from the SSH handshake rejecting
+// our credentials, as opposed to a dial/network-level failure, so callers
+// know it's safe to retry with a different auth method.
+func isAuthFailure(err error) bool {
+	if _, ok := err.(net.Error); ok {
+		return false
+	}
+
+	return strings.Contains(err.Error(), "unable to authenticate")
+}
+
+func passwordAuthMethods(h Host) []ssh.AuthMethod {
+	return []ssh.AuthMethod{
+		ssh.PasswordCallback(func() (string, error) {
+			return resolvePassword(h)
+		}),
+		ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+			pass, err := resolvePassword(h)
+			if err != nil {
+				return nil, err
+			}
+
+			answers := make([]string, len(questions))
+			for i := range answers {
+				answers[i] = pass
+			}
+
+			return answers, nil
+		}),
+	}
+}
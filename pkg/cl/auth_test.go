@@ -0,0 +1,102 @@
+package cl
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func pkcs1PEM(t *testing.T, passphrase string) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %s", err)
+	}
+
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+
+	if passphrase != "" {
+		//lint:ignore SA1019 exercising the legacy encrypted-PEM format cl must still read
+		block, err = x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, []byte(passphrase), x509.PEMCipherAES256)
+		if err != nil {
+			t.Fatalf("x509.EncryptPEMBlock: %s", err)
+		}
+	}
+
+	return pem.EncodeToMemory(block)
+}
+
+func openSSHPEM(t *testing.T, key interface{}, passphrase string) []byte {
+	t.Helper()
+
+	var block *pem.Block
+	var err error
+
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, "")
+	}
+
+	if err != nil {
+		t.Fatalf("ssh.MarshalPrivateKey: %s", err)
+	}
+
+	return pem.EncodeToMemory(block)
+}
+
+func TestParseSignerPKCS1(t *testing.T) {
+	for _, passphrase := range []string{"", "hunter2"} {
+		key := pkcs1PEM(t, passphrase)
+		cert := filepath.Join(t.TempDir(), "id_rsa")
+
+		if passphrase != "" {
+			t.Setenv("CL_KEY_PASSPHRASE_"+sanitizeEnvName(filepath.Base(cert)), passphrase)
+		}
+
+		if _, err := parseSigner(cert, key); err != nil {
+			t.Errorf("parseSigner(passphrase=%q): %s", passphrase, err)
+		}
+	}
+}
+
+func TestParseSignerOpenSSH(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey: %s", err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %s", err)
+	}
+
+	keys := map[string]interface{}{
+		"ecdsa":   ecKey,
+		"ed25519": edKey,
+	}
+
+	for name, key := range keys {
+		for _, passphrase := range []string{"", "hunter2"} {
+			pemBytes := openSSHPEM(t, key, passphrase)
+			cert := filepath.Join(t.TempDir(), "id_"+name)
+
+			if passphrase != "" {
+				t.Setenv("CL_KEY_PASSPHRASE_"+sanitizeEnvName(filepath.Base(cert)), passphrase)
+			}
+
+			if _, err := parseSigner(cert, pemBytes); err != nil {
+				t.Errorf("parseSigner(%s, passphrase=%q): %s", name, passphrase, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package cl drives commands and file transfers across clusters of hosts
+// over SSH.
+package cl
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Host describes a single SSH endpoint to run a command against.
+type Host struct {
+	User     string
+	Addr     string
+	Identity string
+	Password string
+}
+
+// ParseHosts reads the hosts file format: cluster names ending in ':'
+// followed by indented "user@host[:port] [identity] [password=...]"
+// entries, one per line. It returns the parsed entries keyed by cluster
+// name.
+func ParseHosts(r io.Reader) map[string][]Host {
+	s := bufio.NewScanner(r)
+	m := make(map[string][]Host)
+
+	curr := ""
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+
+		if line == "" {
+			continue
+		}
+
+		end := len(line) - 1
+
+		if line[end] == ':' {
+			curr = line[:end]
+			continue
+		}
+
+		h := Host{
+			User:     os.Getenv("USER"),
+			Identity: filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa"),
+		}
+
+		if _, ok := m[curr]; !ok {
+			m[curr] = make([]Host, 0)
+		}
+
+		if strings.Contains(line, " ") {
+			parts := strings.Fields(line)
+
+			for _, extra := range parts[1:] {
+				if strings.HasPrefix(extra, "password=") {
+					h.Password = strings.TrimPrefix(extra, "password=")
+					continue
+				}
+
+				h.Identity = extra
+
+				if h.Identity[0] == '~' {
+					h.Identity = strings.Replace(h.Identity, "~", os.Getenv("HOME"), 1)
+				}
+			}
+
+			line = parts[0]
+		}
+
+		if strings.Contains(line, "@") {
+			parts := strings.Split(line, "@")
+
+			h.User = parts[0]
+			line = parts[1]
+		}
+
+		host, port, _ := net.SplitHostPort(line)
+
+		if host == "" {
+			host = line
+		}
+
+		if port == "" {
+			port = "22"
+		}
+
+		h.Addr = net.JoinHostPort(host, port)
+
+		m[curr] = append(m[curr], h)
+	}
+
+	return m
+}
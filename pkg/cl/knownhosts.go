@@ -0,0 +1,141 @@
+package cl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyMismatchError is returned when a host presents a key that
+// contradicts the one recorded in known_hosts — a possible MITM attack.
+type HostKeyMismatchError struct {
+	Host string
+	Err  error
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key mismatch for %s: %s", e.Host, e.Err)
+}
+
+func (e *HostKeyMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownHostError is returned when a host has never been recorded in
+// known_hosts and TOFU is disabled. Unlike HostKeyMismatchError, this is
+// not evidence of a changed key, just an unrecognized one.
+type UnknownHostError struct {
+	Host string
+	Err  error
+}
+
+func (e *UnknownHostError) Error() string {
+	return fmt.Sprintf("unknown host %s: %s", e.Host, e.Err)
+}
+
+func (e *UnknownHostError) Unwrap() error {
+	return e.Err
+}
+
+// knownHostsVerifier wraps a known_hosts file and, when tofu is set, trusts
+// and records never-before-seen host keys instead of rejecting them. Writes
+// to the file are serialized and flock'd so that the many per-host
+// goroutines a Runner spawns don't corrupt it by writing concurrently.
+type knownHostsVerifier struct {
+	mu   sync.Mutex
+	path string
+	tofu bool
+	cb   ssh.HostKeyCallback
+}
+
+func newKnownHostsVerifier(path string, tofu bool) (*knownHostsVerifier, error) {
+	if path == "" {
+		path = filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts")
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+	}
+
+	cb, err := knownhosts.New(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &knownHostsVerifier{path: path, tofu: tofu, cb: cb}, nil
+}
+
+func (v *knownHostsVerifier) HostKeyCallback() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		v.mu.Lock()
+		cb := v.cb
+		v.mu.Unlock()
+
+		err := cb(hostname, remote, key)
+
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) > 0 {
+			return &HostKeyMismatchError{Host: hostname, Err: err}
+		}
+
+		if !v.tofu {
+			return &UnknownHostError{Host: hostname, Err: err}
+		}
+
+		if err := v.trust(hostname, remote, key); err != nil {
+			return fmt.Errorf("trusting %s: %w", hostname, err)
+		}
+
+		return nil
+	}
+}
+
+// trust appends a newly seen host key to the known_hosts file under an
+// exclusive flock, so concurrent goroutines running against a cluster
+// append one entry at a time instead of interleaving writes, then reloads
+// v.cb from the updated file so the host is recognized without a second
+// append if it's encountered again in this process.
+func (v *knownHostsVerifier) trust(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	f, err := os.OpenFile(v.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return err
+	}
+
+	cb, err := knownhosts.New(v.path)
+	if err != nil {
+		return err
+	}
+
+	v.cb = cb
+
+	return nil
+}
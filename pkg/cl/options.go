@@ -0,0 +1,63 @@
+package cl
+
+import (
+	"io"
+	"time"
+)
+
+// Option configures a Runner. Apply one or more to NewRunner.
+type Option func(*Runner)
+
+// WithHosts supplies the hosts file contents the Runner parses clusters
+// from. Required.
+func WithHosts(r io.Reader) Option {
+	return func(rn *Runner) { rn.hosts = r }
+}
+
+// WithIdentityFiles sets the private key files tried, in order, for each
+// host. Each is tried until one authenticates successfully.
+func WithIdentityFiles(files ...string) Option {
+	return func(rn *Runner) { rn.identityFiles = files }
+}
+
+// WithAgent enables authentication via the identities held by the
+// ssh-agent listening on SSH_AUTH_SOCK, tried alongside any identity
+// files.
+func WithAgent() Option {
+	return func(rn *Runner) { rn.useAgent = true }
+}
+
+// WithKnownHosts sets the known_hosts file used to verify host keys.
+// Defaults to ~/.ssh/known_hosts.
+func WithKnownHosts(path string) Option {
+	return func(rn *Runner) { rn.knownHostsPath = path }
+}
+
+// WithTOFU trusts and records host keys the known_hosts file has never
+// seen before, instead of rejecting them.
+func WithTOFU() Option {
+	return func(rn *Runner) { rn.tofu = true }
+}
+
+// WithConcurrency bounds how many hosts the Runner dials at once. The
+// default is unbounded (one goroutine per host).
+func WithConcurrency(n int) Option {
+	return func(rn *Runner) { rn.concurrency = n }
+}
+
+// WithTimeout sets the SSH dial timeout per host. Defaults to 60s.
+func WithTimeout(d time.Duration) Option {
+	return func(rn *Runner) { rn.timeout = d }
+}
+
+// WithLogger directs a line of text per successful host run to w. Defaults
+// to io.Discard.
+func WithLogger(w io.Writer) Option {
+	return func(rn *Runner) { rn.logger = w }
+}
+
+// WithChecksum makes Put and Get skip any file whose destination already
+// has a matching SHA-256 sum, instead of always overwriting it.
+func WithChecksum() Option {
+	return func(rn *Runner) { rn.checksum = true }
+}
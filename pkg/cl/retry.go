@@ -0,0 +1,121 @@
+package cl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	maxAttempts  = 4
+	backoffBase  = 500 * time.Millisecond
+	backoffCap   = 30 * time.Second
+	backoffScale = 2
+)
+
+// AttemptError reports how a dial ultimately failed after retries were
+// exhausted, so a caller can print a summary with attempt count and
+// elapsed time alongside the underlying error.
+type AttemptError struct {
+	Host     string
+	Attempts int
+	Elapsed  time.Duration
+	Err      error
+}
+
+func (e *AttemptError) Error() string {
+	return fmt.Sprintf("%s: %s after %d attempt(s), %s", e.Host, e.Err, e.Attempts, e.Elapsed)
+}
+
+func (e *AttemptError) Unwrap() error {
+	return e.Err
+}
+
+// isTransient reports whether err looks like a dial or handshake failure
+// worth retrying: a network-level error, an EOF (the remote closed the
+// connection mid-handshake), or an i/o timeout.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "i/o timeout")
+}
+
+// isTerminal reports whether err should never be retried: the command ran
+// and merely exited non-zero, or the credentials offered were rejected.
+func isTerminal(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return true
+	}
+
+	return isAuthFailure(err)
+}
+
+// backoff returns the delay before retry attempt n (1-based), following
+// base 500ms, factor 2, capped at 30s, with up to 50% jitter.
+func backoff(attempt int) time.Duration {
+	d := backoffBase
+
+	for i := 1; i < attempt; i++ {
+		d *= backoffScale
+
+		if d > backoffCap {
+			d = backoffCap
+			break
+		}
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(d)/2 + 1))
+
+	return d/2 + jitter
+}
+
+// dialWithRetry calls dial up to maxAttempts times, backing off between
+// transient failures, and returns immediately on a terminal error.
+func (rn *Runner) dialWithRetry(ctx context.Context, h Host, cert string, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, int, error) {
+	var lastErr error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		conn, err := rn.dial(h, cert, hostKeyCallback)
+
+		if err == nil {
+			return conn, attempt, nil
+		}
+
+		lastErr = err
+
+		if isTerminal(err) || !isTransient(err) || attempt == maxAttempts {
+			return nil, attempt, err
+		}
+
+		select {
+		case <-time.After(backoff(attempt)):
+		case <-ctx.Done():
+			return nil, attempt, ctx.Err()
+		}
+	}
+
+	return nil, maxAttempts, lastErr
+}
@@ -0,0 +1,65 @@
+package cl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("wrapped: %w", &net.OpError{Op: "dial", Err: errors.New("refused")}), true},
+		{errors.New("read tcp: i/o timeout"), true},
+		{errors.New("ssh: unable to authenticate"), false},
+	}
+
+	for _, c := range cases {
+		if got := isTransient(c.err); got != c.want {
+			t.Errorf("isTransient(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&ssh.ExitError{}, true},
+		{errors.New("ssh: handshake failed: ssh: unable to authenticate"), true},
+		{errors.New("read tcp: i/o timeout"), false},
+	}
+
+	for _, c := range cases {
+		if got := isTerminal(c.err); got != c.want {
+			t.Errorf("isTerminal(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestBackoffCapped(t *testing.T) {
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if d := backoff(attempt); d > backoffCap {
+			t.Errorf("backoff(%d) = %s, want <= %s", attempt, d, backoffCap)
+		}
+	}
+}
+
+func TestAttemptErrorUnwrap(t *testing.T) {
+	inner := errors.New("boom")
+	err := &AttemptError{Host: "h", Attempts: 3, Elapsed: time.Second, Err: inner}
+
+	if !errors.Is(err, inner) {
+		t.Errorf("errors.Is(err, inner) = false, want true")
+	}
+}
@@ -0,0 +1,204 @@
+package cl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Result is what Run produces for a single host once its command has
+// finished, or failed to run at all.
+type Result struct {
+	Host     Host
+	Cert     string
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Attempts int
+	Err      error
+	Duration time.Duration
+}
+
+// Runner executes a command, or an SFTP-style transfer, across every host
+// in a cluster. Construct one with NewRunner and one or more Options.
+type Runner struct {
+	hosts          io.Reader
+	identityFiles  []string
+	useAgent       bool
+	knownHostsPath string
+	tofu           bool
+	concurrency    int
+	timeout        time.Duration
+	logger         io.Writer
+	checksum       bool
+
+	agentOnce   sync.Once
+	agentClient agent.Agent
+	agentErr    error
+}
+
+// NewRunner builds a Runner from the given Options.
+func NewRunner(opts ...Option) *Runner {
+	rn := &Runner{
+		timeout: 60 * time.Second,
+		logger:  ioutil.Discard,
+	}
+
+	for _, opt := range opts {
+		opt(rn)
+	}
+
+	if len(rn.identityFiles) == 0 {
+		rn.identityFiles = []string{filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa")}
+	}
+
+	return rn
+}
+
+// Run parses the hosts Runner was built with, looks up cluster, and runs
+// cmd on every host in it concurrently (bounded by WithConcurrency, if
+// set). It returns a channel with one Result per host, closed once every
+// host has finished. Once ctx is cancelled, hosts that have not yet
+// delivered their Result send none.
+func (rn *Runner) Run(ctx context.Context, cluster string, cmd string) (<-chan Result, error) {
+	return rn.forEachHost(ctx, cluster, func(ctx context.Context, h Host, hostKeyCallback ssh.HostKeyCallback) Result {
+		return rn.runHost(ctx, h, cmd, hostKeyCallback)
+	})
+}
+
+func (rn *Runner) runHost(ctx context.Context, h Host, cmd string, hostKeyCallback ssh.HostKeyCallback) Result {
+	start := time.Now()
+
+	certs := rn.identityFiles
+	if len(certs) == 0 && rn.useAgent {
+		certs = []string{""}
+	}
+
+	var lastErr error
+	var lastAttempts int
+
+	for _, cert := range certs {
+		stdout, stderr, exitCode, attempts, err := rn.dialAndRun(ctx, h, cmd, cert, hostKeyCallback)
+
+		lastAttempts = attempts
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		fmt.Fprintf(rn.logger, "%s@%s %s\n", h.User, h.Addr, cert)
+
+		return Result{
+			Host:     h,
+			Cert:     cert,
+			Stdout:   stdout,
+			Stderr:   stderr,
+			ExitCode: exitCode,
+			Attempts: attempts,
+			Duration: time.Since(start),
+		}
+	}
+
+	elapsed := time.Since(start)
+
+	return Result{
+		Host:     h,
+		Attempts: lastAttempts,
+		Err:      &AttemptError{Host: h.Addr, Attempts: lastAttempts, Elapsed: elapsed, Err: lastErr},
+		Duration: elapsed,
+	}
+}
+
+func (rn *Runner) dialAndRun(ctx context.Context, h Host, cmd string, cert string, hostKeyCallback ssh.HostKeyCallback) ([]byte, []byte, int, int, error) {
+	conn, attempts, err := rn.dialWithRetry(ctx, h, cert, hostKeyCallback)
+
+	if err != nil {
+		return nil, nil, 0, attempts, err
+	}
+
+	defer conn.Close()
+
+	sess, err := conn.NewSession()
+
+	if err != nil {
+		return nil, nil, 0, attempts, err
+	}
+
+	defer sess.Close()
+
+	var stdout, stderr bytes.Buffer
+	sess.Stdout = &stdout
+	sess.Stderr = &stderr
+
+	err = sess.Run(cmd)
+
+	exitCode := 0
+
+	if err != nil {
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			exitCode = exitErr.ExitStatus()
+			err = nil
+		}
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), exitCode, attempts, err
+}
+
+func (rn *Runner) dial(h Host, cert string, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, error) {
+	var methods []ssh.AuthMethod
+
+	if rn.useAgent {
+		if am, err := rn.agentAuthMethod(); err == nil {
+			methods = append(methods, am)
+		}
+	}
+
+	if cert != "" {
+		key, err := ioutil.ReadFile(cert)
+
+		if err != nil {
+			if len(methods) == 0 {
+				return nil, err
+			}
+		} else if signer, err := parseSigner(cert, key); err != nil {
+			if len(methods) == 0 {
+				return nil, err
+			}
+		} else {
+			methods = append(methods, ssh.PublicKeys(signer))
+		}
+	}
+
+	cfg := &ssh.ClientConfig{
+		User:            h.User,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         rn.timeout,
+	}
+
+	var conn *ssh.Client
+	var err error
+
+	if len(methods) > 0 {
+		conn, err = ssh.Dial("tcp", h.Addr, cfg)
+	} else {
+		err = fmt.Errorf("unable to authenticate, no supported methods remain")
+	}
+
+	if conn == nil && isAuthFailure(err) {
+		cfg.Auth = passwordAuthMethods(h)
+		conn, err = ssh.Dial("tcp", h.Addr, cfg)
+	}
+
+	return conn, err
+}
@@ -0,0 +1,390 @@
+package cl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Put copies local (a file or directory) to remote on every host in
+// cluster, preserving mode and mtime. Directories are copied recursively.
+func (rn *Runner) Put(ctx context.Context, cluster string, local string, remote string) (<-chan Result, error) {
+	return rn.forEachHost(ctx, cluster, func(ctx context.Context, h Host, hostKeyCallback ssh.HostKeyCallback) Result {
+		return rn.putHost(ctx, h, local, remote, hostKeyCallback)
+	})
+}
+
+// Get copies remote (a file or directory) from every host in cluster into
+// localDirTemplate, preserving mode and mtime. The literal string "{host}"
+// in localDirTemplate is replaced with the host's address, every character
+// other than an ASCII letter or digit turned into '_' (so "10.0.0.1:22"
+// becomes "10_0_0_1_22"), so that files from different hosts don't collide.
+func (rn *Runner) Get(ctx context.Context, cluster string, remote string, localDirTemplate string) (<-chan Result, error) {
+	return rn.forEachHost(ctx, cluster, func(ctx context.Context, h Host, hostKeyCallback ssh.HostKeyCallback) Result {
+		localDir := strings.ReplaceAll(localDirTemplate, "{host}", sanitizeEnvName(h.Addr))
+		return rn.getHost(ctx, h, remote, localDir, hostKeyCallback)
+	})
+}
+
+// forEachHost runs fn against every host in cluster with the same
+// concurrency bound, verifier, and Result plumbing that Run uses.
+func (rn *Runner) forEachHost(ctx context.Context, cluster string, fn func(context.Context, Host, ssh.HostKeyCallback) Result) (<-chan Result, error) {
+	if rn.hosts == nil {
+		return nil, fmt.Errorf("cl: WithHosts is required")
+	}
+
+	clusters := ParseHosts(rn.hosts)
+
+	hosts, ok := clusters[cluster]
+
+	if !ok {
+		return nil, fmt.Errorf("cl: unknown cluster %q", cluster)
+	}
+
+	verifier, err := newKnownHostsVerifier(rn.knownHostsPath, rn.tofu)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var sem chan struct{}
+	if rn.concurrency > 0 {
+		sem = make(chan struct{}, rn.concurrency)
+	}
+
+	results := make(chan Result)
+	wg := &sync.WaitGroup{}
+
+	for _, h := range hosts {
+		wg.Add(1)
+
+		go func(h Host) {
+			defer wg.Done()
+
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			res := fn(ctx, h, verifier.HostKeyCallback())
+
+			select {
+			case results <- res:
+			case <-ctx.Done():
+			}
+		}(h)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+func (rn *Runner) sftpClient(ctx context.Context, h Host, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, *sftp.Client, int, error) {
+	certs := rn.identityFiles
+	if len(certs) == 0 && rn.useAgent {
+		certs = []string{""}
+	}
+
+	var lastErr error
+	var lastAttempts int
+
+	for _, cert := range certs {
+		conn, attempts, err := rn.dialWithRetry(ctx, h, cert, hostKeyCallback)
+
+		lastAttempts = attempts
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		sc, err := sftp.NewClient(conn)
+
+		if err != nil {
+			conn.Close()
+			lastErr = err
+			continue
+		}
+
+		return conn, sc, attempts, nil
+	}
+
+	return nil, nil, lastAttempts, &AttemptError{Host: h.Addr, Attempts: lastAttempts, Err: lastErr}
+}
+
+func (rn *Runner) putHost(ctx context.Context, h Host, local string, remote string, hostKeyCallback ssh.HostKeyCallback) Result {
+	start := time.Now()
+
+	conn, sc, attempts, err := rn.sftpClient(ctx, h, hostKeyCallback)
+
+	if err != nil {
+		return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+	}
+
+	defer conn.Close()
+	defer sc.Close()
+
+	info, err := os.Stat(local)
+
+	if err != nil {
+		return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+	}
+
+	if info.IsDir() {
+		err = filepath.Walk(local, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(local, p)
+			if err != nil {
+				return err
+			}
+
+			dst := path.Join(remote, filepath.ToSlash(rel))
+
+			if fi.IsDir() {
+				return sc.MkdirAll(dst)
+			}
+
+			return rn.putFile(conn, sc, p, dst, fi)
+		})
+	} else {
+		err = rn.putFile(conn, sc, local, remote, info)
+	}
+
+	return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+}
+
+func (rn *Runner) putFile(conn *ssh.Client, sc *sftp.Client, local string, remote string, info os.FileInfo) error {
+	if rn.checksum {
+		match, err := filesMatch(conn, sc, local, remote)
+		if err != nil {
+			return err
+		}
+		if match {
+			return nil
+		}
+	}
+
+	src, err := os.Open(local)
+
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	if err := sc.MkdirAll(path.Dir(remote)); err != nil {
+		return err
+	}
+
+	dst, err := sc.Create(remote)
+
+	if err != nil {
+		return err
+	}
+
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+
+	if err := dst.Chmod(info.Mode()); err != nil {
+		return err
+	}
+
+	return sc.Chtimes(remote, info.ModTime(), info.ModTime())
+}
+
+func (rn *Runner) getHost(ctx context.Context, h Host, remote string, localDir string, hostKeyCallback ssh.HostKeyCallback) Result {
+	start := time.Now()
+
+	conn, sc, attempts, err := rn.sftpClient(ctx, h, hostKeyCallback)
+
+	if err != nil {
+		return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+	}
+
+	defer conn.Close()
+	defer sc.Close()
+
+	info, err := sc.Stat(remote)
+
+	if err != nil {
+		return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+	}
+
+	if info.IsDir() {
+		walker := sc.Walk(remote)
+
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+			}
+
+			rel, err := filepath.Rel(remote, walker.Path())
+			if err != nil {
+				return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+			}
+
+			dst := filepath.Join(localDir, rel)
+
+			if walker.Stat().IsDir() {
+				if err := os.MkdirAll(dst, 0755); err != nil {
+					return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+				}
+				continue
+			}
+
+			if err := rn.getFile(conn, sc, walker.Path(), dst, walker.Stat()); err != nil {
+				return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+			}
+		}
+	} else {
+		if err := os.MkdirAll(localDir, 0755); err != nil {
+			return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+		}
+
+		dst := filepath.Join(localDir, path.Base(remote))
+
+		if err := rn.getFile(conn, sc, remote, dst, info); err != nil {
+			return Result{Host: h, Attempts: attempts, Err: err, Duration: time.Since(start)}
+		}
+	}
+
+	return Result{Host: h, Attempts: attempts, Duration: time.Since(start)}
+}
+
+func (rn *Runner) getFile(conn *ssh.Client, sc *sftp.Client, remote string, local string, info os.FileInfo) error {
+	if rn.checksum {
+		match, err := filesMatch(conn, sc, local, remote)
+		if err != nil {
+			return err
+		}
+		if match {
+			return nil
+		}
+	}
+
+	src, err := sc.Open(remote)
+
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return err
+	}
+
+	dst, err := os.Create(local)
+
+	if err != nil {
+		return err
+	}
+
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+
+	if err := dst.Chmod(info.Mode()); err != nil {
+		return err
+	}
+
+	return os.Chtimes(local, info.ModTime(), info.ModTime())
+}
+
+// filesMatch reports whether local and remote already have identical
+// content, by comparing SHA-256 sums. The remote sum is computed with
+// sha256sum over the existing SSH connection so --checksum mode never has
+// to transfer a file just to find out it can be skipped.
+func filesMatch(conn *ssh.Client, sc *sftp.Client, local string, remote string) (bool, error) {
+	localSum, err := localSHA256(local)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	remoteSum, err := remoteSHA256(conn, remote)
+
+	if err != nil || remoteSum == "" {
+		return false, err
+	}
+
+	return localSum == remoteSum, nil
+}
+
+func localSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func remoteSHA256(conn *ssh.Client, path string) (string, error) {
+	sess, err := conn.NewSession()
+
+	if err != nil {
+		return "", err
+	}
+
+	defer sess.Close()
+
+	var out bytes.Buffer
+	sess.Stdout = &out
+
+	if err := sess.Run(fmt.Sprintf("sha256sum -- %s 2>/dev/null || true", shellQuote(path))); err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(out.String())
+
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	return fields[0], nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
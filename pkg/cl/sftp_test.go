@@ -0,0 +1,46 @@
+package cl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalSHA256(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	sum, err := localSHA256(path)
+	if err != nil {
+		t.Fatalf("localSHA256: %s", err)
+	}
+
+	// sha256("hello")
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if sum != want {
+		t.Errorf("localSHA256(%q) = %s, want %s", path, sum, want)
+	}
+}
+
+func TestLocalSHA256MissingFile(t *testing.T) {
+	if _, err := localSHA256(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("localSHA256(missing) err = %v, want IsNotExist", err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	cases := map[string]string{
+		"/tmp/plain":   `'/tmp/plain'`,
+		"/tmp/o'brien": `'/tmp/o'\''brien'`,
+		"":             `''`,
+	}
+
+	for in, want := range cases {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}